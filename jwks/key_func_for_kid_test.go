@@ -0,0 +1,85 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFuncForKIDRateLimitsForcedRefresh(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	cp := NewCachingProvider(issuerURL, time.Hour,
+		WithCustomJWKSURI(jwksURI),
+		WithSynchronousRefresh(true),
+		WithMinRefreshInterval(time.Minute),
+	)
+
+	ctx := context.Background()
+
+	// Populate the cache, so that kid1 is known and further calls only ever
+	// see the unknown kid.
+	_, err = cp.KeyFunc(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, atomic.LoadInt32(&requests))
+
+	// A flood of requests bearing an unknown kid should trigger at most one
+	// forced refresh, not one per call, since they all land within
+	// minRefreshInterval of each other.
+	for i := 0; i < 20; i++ {
+		_, err := cp.KeyFuncForKID(ctx, "unknown-kid")
+		require.NoError(t, err)
+	}
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests), "expected exactly one forced refresh for the flood of unknown kids")
+}
+
+func TestKeyFuncForKIDSkipsForceRefreshWhenKIDIsCached(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	cp := NewCachingProvider(issuerURL, time.Hour, WithCustomJWKSURI(jwksURI), WithSynchronousRefresh(true))
+
+	ctx := context.Background()
+
+	_, err = cp.KeyFuncForKID(ctx, "kid1")
+	require.NoError(t, err)
+	_, err = cp.KeyFuncForKID(ctx, "kid1")
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests), "a cached, known kid should never trigger a refresh")
+}
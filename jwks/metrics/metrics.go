@@ -0,0 +1,69 @@
+// Package metrics provides a ready-made jwks.ProviderObserver backed by
+// expvar counters, so that a Provider or CachingProvider's fetch/cache
+// behavior shows up on /debug/vars without pulling in a metrics client
+// library. Implementing jwks.ProviderObserver against a real client (e.g.
+// Prometheus' client_golang) follows the same shape — see Observer for the
+// counters to mirror.
+package metrics
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/otee-as/go-jwt-middleware/jwks"
+)
+
+var _ jwks.ProviderObserver = (*Observer)(nil)
+
+// Observer is a jwks.ProviderObserver that records fetch/cache/error counts
+// and total fetch duration into expvar. Build one with NewObserver per
+// process and pass it to jwks.WithObserver.
+type Observer struct {
+	fetchTotal        *expvar.Int
+	fetchErrorsTotal  *expvar.Int
+	cacheHitsTotal    *expvar.Int
+	staleServedTotal  *expvar.Int
+	fetchDurationSecs *expvar.Float
+}
+
+// NewObserver builds an Observer and publishes its counters via
+// expvar.Publish under "<name>.fetch_total", "<name>.fetch_errors_total",
+// "<name>.cache_hits_total", "<name>.stale_served_total" and
+// "<name>.fetch_duration_seconds_sum". name defaults to "jwks" if empty.
+// As with expvar.Publish, calling NewObserver with the same name twice
+// panics — build one Observer per process.
+func NewObserver(name string) *Observer {
+	if name == "" {
+		name = "jwks"
+	}
+
+	return &Observer{
+		fetchTotal:        expvar.NewInt(name + ".fetch_total"),
+		fetchErrorsTotal:  expvar.NewInt(name + ".fetch_errors_total"),
+		cacheHitsTotal:    expvar.NewInt(name + ".cache_hits_total"),
+		staleServedTotal:  expvar.NewInt(name + ".stale_served_total"),
+		fetchDurationSecs: expvar.NewFloat(name + ".fetch_duration_seconds_sum"),
+	}
+}
+
+// OnFetch implements jwks.ProviderObserver.
+func (o *Observer) OnFetch(_ context.Context, _, _ string, duration time.Duration, _ int, _ error) {
+	o.fetchTotal.Add(1)
+	o.fetchDurationSecs.Add(duration.Seconds())
+}
+
+// OnCacheHit implements jwks.ProviderObserver.
+func (o *Observer) OnCacheHit(context.Context, string) {
+	o.cacheHitsTotal.Add(1)
+}
+
+// OnRefreshError implements jwks.ProviderObserver.
+func (o *Observer) OnRefreshError(context.Context, string, int, error) {
+	o.fetchErrorsTotal.Add(1)
+}
+
+// OnStaleServed implements jwks.ProviderObserver.
+func (o *Observer) OnStaleServed(context.Context, string, time.Duration) {
+	o.staleServedTotal.Add(1)
+}
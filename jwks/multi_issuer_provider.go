@@ -0,0 +1,113 @@
+package jwks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// IssuerAllowFunc reports whether issuer is a trusted issuer, returning the
+// *url.URL to use for it (typically url.Parse(issuer), but callers may
+// rewrite it, e.g. to point at an internal mirror) and true if so. It is
+// consulted before any network call is made for an issuer, so that
+// MultiIssuerProvider never fetches JWKS for an issuer it doesn't trust.
+type IssuerAllowFunc func(ctx context.Context, issuer string) (*url.URL, bool)
+
+// MultiIssuerProvider fans out to a per-issuer CachingProvider for
+// deployments that federate several trusted IdPs, e.g. B2B/multi-tenant
+// setups. Unlike CachingProvider, whose cache is keyed by hostname but only
+// ever holds the single configured IssuerURL, MultiIssuerProvider resolves
+// and caches each issuer independently and rejects any issuer not vetted by
+// AllowFunc before ever making a request to it.
+type MultiIssuerProvider struct {
+	AllowFunc IssuerAllowFunc
+	CacheTTL  time.Duration
+
+	providerOpts []ProviderOption
+	cachingOpts  []CachingProviderOption
+
+	mu        sync.Mutex
+	providers map[string]*CachingProvider
+}
+
+// NewMultiIssuerProvider builds a MultiIssuerProvider that trusts whatever
+// issuers allowFunc allows, caching each one's JWKS for cacheTTL (see
+// NewCachingProvider for its zero-value behavior). opts are applied to every
+// per-issuer CachingProvider it creates.
+func NewMultiIssuerProvider(allowFunc IssuerAllowFunc, cacheTTL time.Duration, opts ...interface{}) *MultiIssuerProvider {
+	var providerOpts []ProviderOption
+	var cachingOpts []CachingProviderOption
+
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case ProviderOption:
+			providerOpts = append(providerOpts, o)
+		case CachingProviderOption:
+			cachingOpts = append(cachingOpts, o)
+		default:
+			panic(fmt.Sprintf("invalid option type: %T", o))
+		}
+	}
+
+	return &MultiIssuerProvider{
+		AllowFunc:    allowFunc,
+		CacheTTL:     cacheTTL,
+		providerOpts: providerOpts,
+		cachingOpts:  cachingOpts,
+		providers:    map[string]*CachingProvider{},
+	}
+}
+
+// KeyFuncForIssuer adheres to the keyFunc signature that the Validator
+// requires, for a given issuer — callers should use it after parsing the
+// token's iss claim. It returns an error without making any network call if
+// issuer isn't allowed by AllowFunc.
+func (m *MultiIssuerProvider) KeyFuncForIssuer(ctx context.Context, issuer string) (interface{}, error) {
+	provider, err := m.providerFor(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.KeyFunc(ctx)
+}
+
+// providerFor returns the CachingProvider for issuer, creating it on first
+// use. A mutex guards the providers map itself; once created, each
+// CachingProvider handles its own refresh contention via its own semaphore,
+// so two tenants refreshing concurrently never block one another.
+func (m *MultiIssuerProvider) providerFor(ctx context.Context, issuer string) (*CachingProvider, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if provider, ok := m.providers[issuer]; ok {
+		return provider, nil
+	}
+
+	issuerURL, ok := m.AllowFunc(ctx, issuer)
+	if !ok {
+		return nil, fmt.Errorf("issuer %q is not on the allow-list", issuer)
+	}
+
+	provider := NewCachingProvider(issuerURL, m.CacheTTL, toOptionSlice(m.providerOpts, m.cachingOpts)...)
+	m.providers[issuer] = provider
+
+	return provider, nil
+}
+
+// toOptionSlice flattens providerOpts and cachingOpts back into the
+// interface{} slice NewCachingProvider accepts.
+func toOptionSlice(providerOpts []ProviderOption, cachingOpts []CachingProviderOption) []interface{} {
+	opts := make([]interface{}, 0, len(providerOpts)+len(cachingOpts))
+
+	for _, opt := range providerOpts {
+		opts = append(opts, opt)
+	}
+
+	for _, opt := range cachingOpts {
+		opts = append(opts, opt)
+	}
+
+	return opts
+}
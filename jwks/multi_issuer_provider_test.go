@@ -0,0 +1,76 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiIssuerProviderRejectsDisallowedIssuerWithoutAnyRequest(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	allowFunc := func(_ context.Context, issuer string) (*url.URL, bool) {
+		if issuer != "https://allowed.example.com" {
+			return nil, false
+		}
+		return jwksURI, true
+	}
+
+	m := NewMultiIssuerProvider(allowFunc, time.Hour, WithCustomJWKSURI(jwksURI))
+
+	_, err = m.KeyFuncForIssuer(context.Background(), "https://disallowed.example.com")
+	require.Error(t, err)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&requests), "no request should ever reach the issuer's JWKS endpoint")
+}
+
+func TestMultiIssuerProviderFetchesAndCachesPerAllowedIssuer(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	allowFunc := func(_ context.Context, issuer string) (*url.URL, bool) {
+		return jwksURI, true
+	}
+
+	m := NewMultiIssuerProvider(allowFunc, time.Hour, WithCustomJWKSURI(jwksURI))
+
+	ctx := context.Background()
+	_, err = m.KeyFuncForIssuer(ctx, "https://tenant-a.example.com")
+	require.NoError(t, err)
+	_, err = m.KeyFuncForIssuer(ctx, "https://tenant-a.example.com")
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests), "a second call for the same issuer should be served from cache")
+
+	_, err = m.KeyFuncForIssuer(ctx, "https://tenant-b.example.com")
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&requests), "a different issuer gets its own independent cache")
+}
@@ -0,0 +1,61 @@
+package jwks
+
+import (
+	"context"
+	"time"
+)
+
+// ProviderObserver is notified of significant events as Provider and
+// CachingProvider fetch and cache JWKS, so that callers can plug in
+// metrics, tracing, or logging without forking this package. Implementations
+// must be safe for concurrent use; they are called inline with the
+// operation they describe, so they should return quickly and hand off any
+// expensive work (e.g. exporting to a remote backend) asynchronously.
+type ProviderObserver interface {
+	// OnFetch is called after every JWKS HTTP fetch attempt against url for
+	// issuer, successful or not, with how long it took and the HTTP status
+	// code received (0 if the request never got a response).
+	OnFetch(ctx context.Context, issuer, url string, duration time.Duration, statusCode int, err error)
+
+	// OnCacheHit is called whenever CachingProvider serves a cached JWKS for
+	// issuer without needing to wait on a refresh.
+	OnCacheHit(ctx context.Context, issuer string)
+
+	// OnRefreshError is called for each failed refresh attempt for issuer,
+	// including attempts that WithRefreshRetry will still retry; attempt is
+	// the 1-based attempt number.
+	OnRefreshError(ctx context.Context, issuer string, attempt int, err error)
+
+	// OnStaleServed is called whenever CachingProvider falls back to
+	// serving a stale cached JWKS for issuer because a refresh failed, with
+	// age being how long past its expiry the cached entry is.
+	OnStaleServed(ctx context.Context, issuer string, age time.Duration)
+}
+
+// NoopObserver is a ProviderObserver whose methods do nothing. It is the
+// default Observer for both Provider and CachingProvider.
+type NoopObserver struct{}
+
+// OnFetch implements ProviderObserver.
+func (NoopObserver) OnFetch(context.Context, string, string, time.Duration, int, error) {}
+
+// OnCacheHit implements ProviderObserver.
+func (NoopObserver) OnCacheHit(context.Context, string) {}
+
+// OnRefreshError implements ProviderObserver.
+func (NoopObserver) OnRefreshError(context.Context, string, int, error) {}
+
+// OnStaleServed implements ProviderObserver.
+func (NoopObserver) OnStaleServed(context.Context, string, time.Duration) {}
+
+// observer returns p.Observer, falling back to NoopObserver when it is nil.
+// Provider's fields have always been safe to set by struct literal (e.g.
+// &Provider{IssuerURL: u}) without going through NewProvider, and Observer
+// must stay safe to leave unset the same way.
+func (p *Provider) observer() ProviderObserver {
+	if p.Observer == nil {
+		return NoopObserver{}
+	}
+
+	return p.Observer
+}
@@ -0,0 +1,145 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeObserver records every call made to it, guarded by a mutex since
+// CachingProvider may invoke it from its background refresh goroutine.
+type fakeObserver struct {
+	mu            sync.Mutex
+	fetches       int
+	cacheHits     int
+	refreshErrors int
+	staleServed   int
+}
+
+func (f *fakeObserver) OnFetch(context.Context, string, string, time.Duration, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetches++
+}
+
+func (f *fakeObserver) OnCacheHit(context.Context, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cacheHits++
+}
+
+func (f *fakeObserver) OnRefreshError(context.Context, string, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refreshErrors++
+}
+
+func (f *fakeObserver) OnStaleServed(context.Context, string, time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.staleServed++
+}
+
+func (f *fakeObserver) snapshot() (fetches, cacheHits, refreshErrors, staleServed int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fetches, f.cacheHits, f.refreshErrors, f.staleServed
+}
+
+func TestCachingProviderNotifiesObserverOfFetchAndCacheHit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	observer := &fakeObserver{}
+	cp := NewCachingProvider(issuerURL, time.Hour, WithCustomJWKSURI(jwksURI), WithObserver(observer))
+
+	ctx := context.Background()
+	_, err = cp.KeyFunc(ctx)
+	require.NoError(t, err)
+
+	_, err = cp.KeyFunc(ctx)
+	require.NoError(t, err)
+
+	fetches, cacheHits, _, _ := observer.snapshot()
+	assert.Equal(t, 1, fetches, "the first call should fetch")
+	assert.Equal(t, 1, cacheHits, "the second call should be served from cache")
+}
+
+func TestCachingProviderNotifiesObserverOfRefreshErrorAndStaleServed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	observer := &fakeObserver{}
+	cp := NewCachingProvider(issuerURL, time.Millisecond,
+		WithCustomJWKSURI(jwksURI),
+		WithObserver(observer),
+		WithSynchronousRefresh(true),
+		WithStaleWhileErrorTTL(time.Hour),
+		WithRefreshRetry(1, time.Millisecond, time.Millisecond),
+	)
+
+	ctx := context.Background()
+
+	// Seed the cache directly with an already-expired entry, so that the
+	// first KeyFunc call goes straight to a refresh against the
+	// always-failing server above.
+	cp.mu.Lock()
+	cp.cache[issuerURL.Hostname()] = cachedJWKS{
+		jwks:      &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1"}}},
+		expiresAt: time.Now().Add(-time.Minute),
+	}
+	cp.mu.Unlock()
+
+	_, err = cp.KeyFunc(ctx)
+	require.NoError(t, err, "a failed refresh within StaleWhileErrorTTL should serve the stale cache")
+
+	_, _, refreshErrors, staleServed := observer.snapshot()
+	assert.Equal(t, 1, refreshErrors)
+	assert.Equal(t, 1, staleServed)
+}
+
+func TestProviderLiteralWithoutObserverDoesNotPanic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	// A *Provider built by struct literal (not NewProvider) has a nil
+	// Observer; KeyFunc must not panic on it.
+	p := &Provider{IssuerURL: issuerURL, CustomJWKSURI: jwksURI, Client: &http.Client{}}
+
+	assert.NotPanics(t, func() {
+		_, err := p.KeyFunc(context.Background())
+		require.NoError(t, err)
+	})
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/otee-as/go-jwt-middleware/internal/oidc"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +17,25 @@ import (
 	"golang.org/x/sync/semaphore"
 )
 
+// minCacheTTL is the floor applied to a provider-advertised TTL so that a
+// misconfigured or overly aggressive Cache-Control/Expires header cannot
+// cause the cache to thrash on every request.
+const minCacheTTL = 2 * time.Minute
+
+// defaultMinRefreshInterval is the default minimum time that must elapse
+// between two forced, out-of-band refreshes of the same issuer's JWKS
+// triggered by KeyFuncForKID. It exists to stop a flood of requests bearing
+// bogus kids from turning into a flood of upstream JWKS fetches.
+const defaultMinRefreshInterval = 30 * time.Second
+
+// Defaults for CachingProvider's stale-if-error and retry behavior.
+const (
+	defaultStaleWhileErrorTTL    = 24 * time.Hour
+	defaultRefreshMaxAttempts    = 3
+	defaultRefreshInitialBackoff = 250 * time.Millisecond
+	defaultRefreshMaxBackoff     = 5 * time.Second
+)
+
 // Provider handles getting JWKS from the specified IssuerURL and exposes
 // KeyFunc which adheres to the keyFunc signature that the Validator requires.
 // Most likely you will want to use the CachingProvider as it handles
@@ -23,6 +45,7 @@ type Provider struct {
 	IssuerURL     *url.URL // Required.
 	CustomJWKSURI *url.URL // Optional.
 	Client        *http.Client
+	Observer      ProviderObserver
 }
 
 // ProviderOption is how options for the Provider are set up.
@@ -33,6 +56,7 @@ func NewProvider(issuerURL *url.URL, opts ...ProviderOption) *Provider {
 	p := &Provider{
 		IssuerURL: issuerURL,
 		Client:    &http.Client{},
+		Observer:  NoopObserver{},
 	}
 
 	for _, opt := range opts {
@@ -58,40 +82,126 @@ func WithCustomClient(c *http.Client) ProviderOption {
 	}
 }
 
+// WithObserver sets the ProviderObserver notified of fetch/cache events on
+// the *Provider. Defaults to NoopObserver, which does nothing. See the
+// jwks/metrics subpackage for a ready-made expvar-backed implementation.
+func WithObserver(observer ProviderObserver) ProviderOption {
+	return func(p *Provider) {
+		p.Observer = observer
+	}
+}
+
 // KeyFunc adheres to the keyFunc signature that the Validator requires.
 // While it returns an interface to adhere to keyFunc, as long as the
 // error is nil the type will be *jose.JSONWebKeySet.
 func (p *Provider) KeyFunc(ctx context.Context) (interface{}, error) {
+	jwks, _, err := p.fetchJWKS(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwks, nil
+}
+
+// fetchJWKS fetches and decodes the JWKS from the IssuerURL or CustomJWKSURI.
+// When parseCacheControl is true, the response's Cache-Control/Expires
+// headers are parsed into a provider-advertised TTL which is returned
+// alongside the JWKS; otherwise the returned time.Duration is zero. This is
+// an internal method so that callers such as CachingProvider can drive their
+// own cache expiry off the advertised TTL while KeyFunc's public signature
+// stays unchanged.
+func (p *Provider) fetchJWKS(ctx context.Context, parseCacheControl bool) (*jose.JSONWebKeySet, time.Duration, error) {
 	jwksURI := p.CustomJWKSURI
 	if jwksURI == nil {
 		wkEndpoints, err := oidc.GetWellKnownEndpointsFromIssuerURL(ctx, p.Client, *p.IssuerURL)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		jwksURI, err = url.Parse(wkEndpoints.JWKSURI)
 		if err != nil {
-			return nil, fmt.Errorf("could not parse JWKS URI from well known endpoints: %w", err)
+			return nil, 0, fmt.Errorf("could not parse JWKS URI from well known endpoints: %w", err)
 		}
 	}
 
 	request, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("could not build request to get JWKS: %w", err)
+		return nil, 0, fmt.Errorf("could not build request to get JWKS: %w", err)
 	}
 
+	start := time.Now()
 	response, err := p.Client.Do(request)
 	if err != nil {
-		return nil, err
+		p.observer().OnFetch(ctx, p.IssuerURL.Hostname(), jwksURI.String(), time.Since(start), 0, err)
+		return nil, 0, err
 	}
 	defer response.Body.Close()
 
 	var jwks jose.JSONWebKeySet
 	if err := json.NewDecoder(response.Body).Decode(&jwks); err != nil {
-		return nil, fmt.Errorf("could not decode jwks: %w", err)
+		err = fmt.Errorf("could not decode jwks: %w", err)
+		p.observer().OnFetch(ctx, p.IssuerURL.Hostname(), jwksURI.String(), time.Since(start), response.StatusCode, err)
+		return nil, 0, err
 	}
 
-	return &jwks, nil
+	p.observer().OnFetch(ctx, p.IssuerURL.Hostname(), jwksURI.String(), time.Since(start), response.StatusCode, nil)
+
+	var advertisedTTL time.Duration
+	if parseCacheControl {
+		advertisedTTL = cacheExpiryFromHeaders(response.Header)
+	}
+
+	return &jwks, advertisedTTL, nil
+}
+
+// cacheExpiryFromHeaders parses the Cache-Control (preferring s-maxage over
+// max-age) and Expires headers of a JWKS HTTP response into a TTL. It
+// returns zero if neither header yields a usable value.
+func cacheExpiryFromHeaders(header http.Header) time.Duration {
+	if ttl, ok := maxAgeFromCacheControl(header.Get("Cache-Control")); ok {
+		return ttl
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			if ttl := time.Until(t); ttl > 0 {
+				return ttl
+			}
+		}
+	}
+
+	return 0
+}
+
+// maxAgeFromCacheControl extracts s-maxage or max-age (in that order of
+// preference, per RFC 7234) from a Cache-Control header value.
+func maxAgeFromCacheControl(cacheControl string) (time.Duration, bool) {
+	if cacheControl == "" {
+		return 0, false
+	}
+
+	directives := map[string]string{}
+	for _, part := range strings.Split(cacheControl, ",") {
+		part = strings.TrimSpace(part)
+		name, value, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	for _, name := range []string{"s-maxage", "max-age"} {
+		value, ok := directives[name]
+		if !ok {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			continue
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	return 0, false
 }
 
 // CachingProvider handles getting JWKS from the specified IssuerURL
@@ -99,18 +209,30 @@ func (p *Provider) KeyFunc(ctx context.Context) (interface{}, error) {
 // to the keyFunc signature that the Validator requires.
 // When the CacheTTL value has been reached, a JWKS refresh will be triggered
 // in the background and the existing cached JWKS will be returned until the
-// JWKS cache is updated, or if the request errors then it will be evicted from
-// the cache.
+// JWKS cache is updated. If the refresh fails, the fetch is retried with
+// exponential backoff (see WithRefreshRetry) and the stale cached JWKS
+// keeps being served for up to StaleWhileErrorTTL rather than being evicted
+// immediately; only once that grace window elapses is it evicted.
+// With WithCacheControl enabled, a fetched JWKS is instead cached for the
+// TTL computed by effectiveTTL from the response's Cache-Control/Expires
+// headers, which can be shorter than CacheTTL.
 // The cache is keyed by the issuer's hostname. The synchronousRefresh
 // field determines whether the refresh is done synchronously or asynchronously.
 // This can be set using the WithSynchronousRefresh option.
 type CachingProvider struct {
 	*Provider
-	CacheTTL           time.Duration
-	mu                 sync.RWMutex
-	cache              map[string]cachedJWKS
-	sem                *semaphore.Weighted
-	synchronousRefresh bool
+	CacheTTL              time.Duration
+	StaleWhileErrorTTL    time.Duration
+	mu                    sync.RWMutex
+	cache                 map[string]cachedJWKS
+	sem                   *semaphore.Weighted
+	synchronousRefresh    bool
+	useCacheControl       bool
+	minRefreshInterval    time.Duration
+	lastForcedRefresh     map[string]time.Time
+	refreshMaxAttempts    int
+	refreshInitialBackoff time.Duration
+	refreshMaxBackoff     time.Duration
 }
 
 type cachedJWKS struct {
@@ -141,11 +263,17 @@ func NewCachingProvider(issuerURL *url.URL, cacheTTL time.Duration, opts ...inte
 		}
 	}
 	cp := &CachingProvider{
-		Provider:           NewProvider(issuerURL, providerOpts...),
-		CacheTTL:           cacheTTL,
-		cache:              map[string]cachedJWKS{},
-		sem:                semaphore.NewWeighted(1),
-		synchronousRefresh: false,
+		Provider:              NewProvider(issuerURL, providerOpts...),
+		CacheTTL:              cacheTTL,
+		StaleWhileErrorTTL:    defaultStaleWhileErrorTTL,
+		cache:                 map[string]cachedJWKS{},
+		sem:                   semaphore.NewWeighted(1),
+		synchronousRefresh:    false,
+		minRefreshInterval:    defaultMinRefreshInterval,
+		lastForcedRefresh:     map[string]time.Time{},
+		refreshMaxAttempts:    defaultRefreshMaxAttempts,
+		refreshInitialBackoff: defaultRefreshInitialBackoff,
+		refreshMaxBackoff:     defaultRefreshMaxBackoff,
 	}
 
 	for _, opt := range cachingOpts {
@@ -168,17 +296,14 @@ func (c *CachingProvider) KeyFunc(ctx context.Context) (interface{}, error) {
 			if !c.synchronousRefresh {
 				go func() {
 					defer c.sem.Release(1)
-					refreshCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+					refreshCtx, cancel := context.WithTimeout(context.Background(), c.backgroundRefreshTimeout())
 					defer cancel()
-					_, err := c.refreshKey(refreshCtx, issuer)
-
-					if err != nil {
-						c.mu.Lock()
-						delete(c.cache, issuer)
-						c.mu.Unlock()
-					}
+					// refreshKey itself decides whether to keep serving a
+					// stale cached entry or evict it on failure.
+					_, _ = c.refreshKey(refreshCtx, issuer)
 				}()
 				c.mu.RUnlock()
+				c.observer().OnCacheHit(ctx, issuer)
 				return cached.jwks, nil
 			} else {
 				c.mu.RUnlock()
@@ -187,6 +312,7 @@ func (c *CachingProvider) KeyFunc(ctx context.Context) (interface{}, error) {
 			}
 		}
 		c.mu.RUnlock()
+		c.observer().OnCacheHit(ctx, issuer)
 		return cached.jwks, nil
 	}
 
@@ -203,19 +329,252 @@ func WithSynchronousRefresh(blocking bool) CachingProviderOption {
 	}
 }
 
+// WithCacheControl enables honoring the JWKS HTTP response's
+// Cache-Control (s-maxage/max-age) and Expires headers when computing how
+// long a fetched JWKS should be cached for. When enabled, the effective
+// expiry is min(providerAdvertisedTTL, CacheTTL), floored at minCacheTTL to
+// prevent a misbehaving issuer from causing the cache to thrash. When
+// disabled (the default), CacheTTL is always used.
+func WithCacheControl(enabled bool) CachingProviderOption {
+	return func(cp *CachingProvider) {
+		cp.useCacheControl = enabled
+	}
+}
+
+// WithStaleWhileErrorTTL sets how long a cached JWKS may keep being served
+// as stale-but-usable after it expires and a refresh fails, before it is
+// finally evicted. It defaults to defaultStaleWhileErrorTTL. Set to zero to
+// evict an already-expired cached JWKS as soon as a refresh of it fails; a
+// cached JWKS that has not yet expired is kept when a refresh fails.
+func WithStaleWhileErrorTTL(ttl time.Duration) CachingProviderOption {
+	return func(cp *CachingProvider) {
+		cp.StaleWhileErrorTTL = ttl
+	}
+}
+
+// WithRefreshRetry configures how a failed JWKS fetch is retried before
+// refreshKey gives up: up to maxAttempts total attempts, with exponential
+// backoff starting at initialBackoff and capped at maxBackoff. The
+// background refresh that KeyFunc triggers once CacheTTL elapses derives its
+// own timeout from this retry budget (see backgroundRefreshTimeout), so a
+// larger retry budget is given the time to actually run instead of being cut
+// off partway through.
+func WithRefreshRetry(maxAttempts int, initialBackoff, maxBackoff time.Duration) CachingProviderOption {
+	return func(cp *CachingProvider) {
+		cp.refreshMaxAttempts = maxAttempts
+		cp.refreshInitialBackoff = initialBackoff
+		cp.refreshMaxBackoff = maxBackoff
+	}
+}
+
+// backgroundRefreshPerAttemptAllowance is how much time
+// backgroundRefreshTimeout budgets for the network round trip of each
+// refresh attempt, on top of the backoff waits between them.
+const backgroundRefreshPerAttemptAllowance = 10 * time.Second
+
+// minBackgroundRefreshTimeout is the floor for backgroundRefreshTimeout, so
+// a misconfigured (e.g. maxAttempts: 1) retry budget still leaves a
+// reasonable amount of time for a single slow fetch.
+const minBackgroundRefreshTimeout = 15 * time.Second
+
+// backgroundRefreshTimeout bounds how long the background refresh goroutine
+// spawned by KeyFunc may run, derived from the configured retry budget: the
+// worst-case total time spent waiting between attempts, plus a fixed
+// allowance per attempt for the fetch itself.
+func (c *CachingProvider) backgroundRefreshTimeout() time.Duration {
+	var totalBackoff time.Duration
+
+	backoff := c.refreshInitialBackoff
+	for attempt := 1; attempt < c.refreshMaxAttempts; attempt++ {
+		totalBackoff += backoff
+
+		backoff *= 2
+		if backoff > c.refreshMaxBackoff {
+			backoff = c.refreshMaxBackoff
+		}
+	}
+
+	timeout := totalBackoff + time.Duration(c.refreshMaxAttempts)*backgroundRefreshPerAttemptAllowance
+	if timeout < minBackgroundRefreshTimeout {
+		timeout = minBackgroundRefreshTimeout
+	}
+
+	return timeout
+}
+
 func (c *CachingProvider) refreshKey(ctx context.Context, issuer string) (interface{}, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	jwks, err := c.Provider.KeyFunc(ctx)
+	jwks, advertisedTTL, err := c.fetchJWKSWithRetry(ctx, issuer)
 	if err != nil {
-		return nil, err
+		return c.staleOrEvictLocked(ctx, issuer, err)
 	}
 
 	c.cache[issuer] = cachedJWKS{
-		jwks:      jwks.(*jose.JSONWebKeySet),
-		expiresAt: time.Now().Add(c.CacheTTL),
+		jwks:      jwks,
+		expiresAt: time.Now().Add(c.effectiveTTL(advertisedTTL)),
 	}
 
 	return jwks, nil
 }
+
+// fetchJWKSWithRetry fetches the JWKS, making up to refreshMaxAttempts
+// attempts in total with exponential backoff (full jitter) between attempts
+// when the fetch fails. It gives up early if ctx is done.
+func (c *CachingProvider) fetchJWKSWithRetry(ctx context.Context, issuer string) (*jose.JSONWebKeySet, time.Duration, error) {
+	backoff := c.refreshInitialBackoff
+	var lastErr error
+
+	for attempt := 1; attempt <= c.refreshMaxAttempts; attempt++ {
+		jwks, advertisedTTL, err := c.Provider.fetchJWKS(ctx, c.useCacheControl)
+		if err == nil {
+			return jwks, advertisedTTL, nil
+		}
+		lastErr = err
+		c.observer().OnRefreshError(ctx, issuer, attempt, err)
+
+		if attempt == c.refreshMaxAttempts {
+			break
+		}
+
+		wait := time.Duration(rand.Int63n(int64(backoff)))
+
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > c.refreshMaxBackoff {
+			backoff = c.refreshMaxBackoff
+		}
+	}
+
+	return nil, 0, lastErr
+}
+
+// staleOrEvictLocked handles a fetch failure for issuer with c.mu already
+// held: if a cached entry exists and is still within StaleWhileErrorTTL of
+// its expiry, it is kept and served as-is; otherwise it is evicted and err
+// is returned.
+func (c *CachingProvider) staleOrEvictLocked(ctx context.Context, issuer string, err error) (interface{}, error) {
+	cached, ok := c.cache[issuer]
+	if !ok {
+		return nil, err
+	}
+
+	age := time.Since(cached.expiresAt)
+	if age > c.StaleWhileErrorTTL {
+		delete(c.cache, issuer)
+		return nil, err
+	}
+
+	if age > 0 {
+		c.observer().OnStaleServed(ctx, issuer, age)
+	}
+
+	return cached.jwks, nil
+}
+
+// effectiveTTL computes the TTL to use for a freshly fetched JWKS, given the
+// TTL (if any) advertised by the provider via Cache-Control/Expires. The
+// minCacheTTL floor only kicks in when the advertised TTL is actually used,
+// so that CacheTTL itself is never overridden when WithCacheControl is off.
+func (c *CachingProvider) effectiveTTL(advertisedTTL time.Duration) time.Duration {
+	if !c.useCacheControl || advertisedTTL <= 0 {
+		return c.CacheTTL
+	}
+
+	ttl := advertisedTTL
+	if c.CacheTTL < ttl {
+		ttl = c.CacheTTL
+	}
+
+	if ttl < minCacheTTL {
+		ttl = minCacheTTL
+	}
+
+	return ttl
+}
+
+// WithMinRefreshInterval sets the minimum time that must elapse between two
+// forced refreshes of the same issuer's JWKS triggered by KeyFuncForKID. It
+// defaults to defaultMinRefreshInterval, and exists to bound the amplification
+// an attacker could otherwise cause by sending requests with bogus kids.
+func WithMinRefreshInterval(interval time.Duration) CachingProviderOption {
+	return func(cp *CachingProvider) {
+		cp.minRefreshInterval = interval
+	}
+}
+
+// KeyFuncForKID behaves like KeyFunc but additionally reacts to a kid that
+// isn't present in the cached JWKS by performing an on-demand refresh,
+// instead of waiting for CacheTTL to elapse. This covers a key rotation at
+// the issuer that happens between two scheduled refreshes. The forced
+// refresh is rate-limited via sem and minRefreshInterval (see
+// WithMinRefreshInterval) so that a flood of
+// requests bearing an unknown or bogus kid cannot be used to amplify load
+// against the issuer; callers that exceed the limit simply fall back to
+// KeyFunc's normal cached/lazy-refresh behavior.
+//
+// Note: this fork does not (yet) contain a Validator type, so there is
+// nothing in-tree to wire the parsed JWT header's kid through from. Callers
+// wanting this behavior need to call KeyFuncForKID themselves, e.g. from a
+// custom keyFunc that first parses the token header.
+func (c *CachingProvider) KeyFuncForKID(ctx context.Context, kid string) (interface{}, error) {
+	issuer := c.IssuerURL.Hostname()
+
+	c.mu.RLock()
+	cached, ok := c.cache[issuer]
+	c.mu.RUnlock()
+
+	if ok && containsKID(cached.jwks, kid) {
+		return c.KeyFunc(ctx)
+	}
+
+	if !c.tryForceRefresh(issuer) {
+		return c.KeyFunc(ctx)
+	}
+	defer c.sem.Release(1)
+
+	return c.refreshKey(ctx, issuer)
+}
+
+// tryForceRefresh reports whether a forced refresh for issuer may proceed
+// now, acquiring sem on success. It enforces minRefreshInterval so that
+// forced refreshes triggered by unknown kids cannot be issued more often
+// than that interval allows.
+func (c *CachingProvider) tryForceRefresh(issuer string) bool {
+	if !c.sem.TryAcquire(1) {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if last, ok := c.lastForcedRefresh[issuer]; ok && time.Since(last) < c.minRefreshInterval {
+		c.sem.Release(1)
+		return false
+	}
+
+	c.lastForcedRefresh[issuer] = time.Now()
+
+	return true
+}
+
+// containsKID reports whether jwks holds a key with the given kid.
+func containsKID(jwks *jose.JSONWebKeySet, kid string) bool {
+	if jwks == nil {
+		return false
+	}
+
+	for _, key := range jwks.Keys {
+		if key.KeyID == kid {
+			return true
+		}
+	}
+
+	return false
+}
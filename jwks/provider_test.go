@@ -0,0 +1,108 @@
+package jwks
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxAgeFromCacheControl(t *testing.T) {
+	tests := []struct {
+		name          string
+		cacheControl  string
+		expectedTTL   time.Duration
+		expectedFound bool
+	}{
+		{
+			name:          "prefers s-maxage over max-age",
+			cacheControl:  "max-age=60, s-maxage=120",
+			expectedTTL:   120 * time.Second,
+			expectedFound: true,
+		},
+		{
+			name:          "falls back to max-age",
+			cacheControl:  "public, max-age=30",
+			expectedTTL:   30 * time.Second,
+			expectedFound: true,
+		},
+		{
+			name:          "ignores a negative max-age",
+			cacheControl:  "max-age=-1",
+			expectedFound: false,
+		},
+		{
+			name:          "ignores a non-numeric max-age",
+			cacheControl:  "max-age=soon",
+			expectedFound: false,
+		},
+		{
+			name:          "empty header",
+			cacheControl:  "",
+			expectedFound: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ttl, found := maxAgeFromCacheControl(test.cacheControl)
+			assert.Equal(t, test.expectedFound, found)
+			if test.expectedFound {
+				assert.Equal(t, test.expectedTTL, ttl)
+			}
+		})
+	}
+}
+
+func TestCacheExpiryFromHeaders(t *testing.T) {
+	t.Run("prefers Cache-Control over Expires", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		header.Set("Expires", time.Now().Add(time.Hour).Format(http.TimeFormat))
+
+		assert.Equal(t, 60*time.Second, cacheExpiryFromHeaders(header))
+	})
+
+	t.Run("falls back to Expires when Cache-Control is absent", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Expires", time.Now().Add(90*time.Second).Format(http.TimeFormat))
+
+		ttl := cacheExpiryFromHeaders(header)
+		assert.Greater(t, ttl, 80*time.Second)
+		assert.LessOrEqual(t, ttl, 90*time.Second)
+	})
+
+	t.Run("ignores an Expires value in the past", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Expires", time.Now().Add(-time.Hour).Format(http.TimeFormat))
+
+		assert.Equal(t, time.Duration(0), cacheExpiryFromHeaders(header))
+	})
+
+	t.Run("returns zero when neither header is present", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), cacheExpiryFromHeaders(http.Header{}))
+	})
+}
+
+func TestCachingProviderEffectiveTTL(t *testing.T) {
+	t.Run("uses CacheTTL when WithCacheControl is disabled, even if advertisedTTL is smaller", func(t *testing.T) {
+		cp := &CachingProvider{CacheTTL: 10 * time.Minute, useCacheControl: false}
+		assert.Equal(t, 10*time.Minute, cp.effectiveTTL(30*time.Second))
+	})
+
+	t.Run("uses the advertised TTL when smaller and WithCacheControl is enabled", func(t *testing.T) {
+		cp := &CachingProvider{CacheTTL: 10 * time.Minute, useCacheControl: true}
+		assert.Equal(t, 5*time.Minute, cp.effectiveTTL(5*time.Minute))
+	})
+
+	t.Run("floors the advertised TTL at minCacheTTL", func(t *testing.T) {
+		cp := &CachingProvider{CacheTTL: 10 * time.Minute, useCacheControl: true}
+		assert.Equal(t, minCacheTTL, cp.effectiveTTL(30*time.Second))
+	})
+
+	t.Run("never exceeds CacheTTL even when the advertised TTL is larger", func(t *testing.T) {
+		cp := &CachingProvider{CacheTTL: 5 * time.Minute, useCacheControl: true}
+		assert.Equal(t, 5*time.Minute, cp.effectiveTTL(time.Hour))
+	})
+}
@@ -0,0 +1,96 @@
+// Package server turns a set of signing keys into a JWKS publisher: an
+// http.Handler that serves them as a spec-compliant JWKS document, plus a
+// helper to publish a matching OpenID Connect discovery document. It is the
+// mirror image of the jwks package, which consumes a JWKS rather than
+// serving one.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// defaultRotationWindow is used when a Handler is built without
+// WithRotationWindow.
+const defaultRotationWindow = 5 * time.Minute
+
+// Handler serves a set of public signing keys as a JWKS document (RFC 7517),
+// suitable for mounting at a well-known path such as /.well-known/jwks.json.
+type Handler struct {
+	keys           []jose.JSONWebKey
+	rotationWindow time.Duration
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithRotationWindow sets how long the served JWKS document may be cached
+// for, surfaced via the Cache-Control max-age directive. It should be set to
+// no more than how long a retired key is kept in the document after
+// rotation, so that relying parties refresh before that key disappears.
+// Defaults to 5 minutes.
+func WithRotationWindow(window time.Duration) Option {
+	return func(h *Handler) {
+		h.rotationWindow = window
+	}
+}
+
+// NewHandler builds a Handler that serves keys — the public halves of the
+// issuer's signing keys — as a JWKS document. keys is copied, so the Handler
+// is unaffected by later mutation of the slice passed in; to rotate keys,
+// build a new Handler (or otherwise replace it, e.g. behind an atomic.Value).
+func NewHandler(keys []jose.JSONWebKey, opts ...Option) *Handler {
+	h := &Handler{
+		keys:           append([]jose.JSONWebKey(nil), keys...),
+		rotationWindow: defaultRotationWindow,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// ServeHTTP writes the JWKS document as JSON, with a Cache-Control header
+// derived from the configured rotation window.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.rotationWindow.Seconds())))
+
+	jwks := jose.JSONWebKeySet{Keys: h.keys}
+	if err := json.NewEncoder(w).Encode(jwks); err != nil {
+		http.Error(w, "could not encode jwks", http.StatusInternalServerError)
+	}
+}
+
+// OpenIDConfiguration is the subset of the OpenID Connect discovery document
+// (https://openid.net/specs/openid-connect-discovery-1_0.html) that a
+// relying party needs in order to locate this issuer's JWKS.
+type OpenIDConfiguration struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// NewOpenIDConfigurationHandler builds an http.Handler that serves the
+// OpenID Connect discovery document for issuer, advertising jwksURI as
+// where to fetch its signing keys. It is typically mounted at
+// /.well-known/openid-configuration alongside a Handler serving jwksURI.
+func NewOpenIDConfigurationHandler(issuer, jwksURI *url.URL) http.Handler {
+	config := OpenIDConfiguration{
+		Issuer:  issuer.String(),
+		JWKSURI: jwksURI.String(),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(config); err != nil {
+			http.Error(w, "could not encode openid configuration", http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerServesJWKSWithCacheControl(t *testing.T) {
+	keys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}}
+	h := NewHandler(keys, WithRotationWindow(2*time.Minute))
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=120", rec.Header().Get("Cache-Control"))
+
+	var jwks jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "kid1", jwks.Keys[0].KeyID)
+}
+
+func TestNewHandlerCopiesKeysSlice(t *testing.T) {
+	keys := []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}}
+	h := NewHandler(keys)
+
+	// Mutating the caller's slice after construction must not affect
+	// keys already served by the Handler.
+	keys[0].KeyID = "mutated"
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var jwks jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "kid1", jwks.Keys[0].KeyID, "the Handler should be unaffected by later mutation of the caller's slice")
+}
+
+func TestNewOpenIDConfigurationHandlerServesIssuerAndJWKSURI(t *testing.T) {
+	issuer, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+	jwksURI, err := url.Parse("https://issuer.example.com/.well-known/jwks.json")
+	require.NoError(t, err)
+
+	handler := NewOpenIDConfigurationHandler(issuer, jwksURI)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var config OpenIDConfiguration
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &config))
+	assert.Equal(t, issuer.String(), config.Issuer)
+	assert.Equal(t, jwksURI.String(), config.JWKSURI)
+}
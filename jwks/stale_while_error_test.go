@@ -0,0 +1,125 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshKeyServesStaleCacheWithinGraceWindow(t *testing.T) {
+	var failing atomic.Bool
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if failing.Load() {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	cp := NewCachingProvider(issuerURL, 10*time.Millisecond,
+		WithCustomJWKSURI(jwksURI),
+		WithSynchronousRefresh(true),
+		WithStaleWhileErrorTTL(time.Hour),
+		WithRefreshRetry(1, time.Millisecond, time.Millisecond),
+	)
+
+	ctx := context.Background()
+
+	jwks, err := cp.KeyFunc(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, jwks)
+
+	time.Sleep(20 * time.Millisecond)
+	failing.Store(true)
+
+	stale, err := cp.KeyFunc(ctx)
+	require.NoError(t, err, "a refresh failure within StaleWhileErrorTTL should serve the stale cache, not an error")
+	assert.Equal(t, jwks, stale)
+}
+
+func TestRefreshKeyEvictsCacheOncePastGraceWindow(t *testing.T) {
+	var failing atomic.Bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing.Load() {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	cp := NewCachingProvider(issuerURL, 10*time.Millisecond,
+		WithCustomJWKSURI(jwksURI),
+		WithSynchronousRefresh(true),
+		WithStaleWhileErrorTTL(5*time.Millisecond),
+		WithRefreshRetry(1, time.Millisecond, time.Millisecond),
+	)
+
+	ctx := context.Background()
+
+	_, err = cp.KeyFunc(ctx)
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	failing.Store(true)
+
+	_, err = cp.KeyFunc(ctx)
+	require.Error(t, err, "a refresh failure past StaleWhileErrorTTL should evict the cache and return the error")
+
+	cp.mu.RLock()
+	_, cached := cp.cache[issuerURL.Hostname()]
+	cp.mu.RUnlock()
+	assert.False(t, cached, "the expired entry should have been evicted")
+}
+
+func TestFetchJWKSWithRetryRetriesUpToMaxAttempts(t *testing.T) {
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	cp := NewCachingProvider(issuerURL, time.Hour,
+		WithCustomJWKSURI(jwksURI),
+		WithRefreshRetry(3, time.Millisecond, time.Millisecond),
+	)
+
+	_, _, err = cp.fetchJWKSWithRetry(context.Background(), issuerURL.Hostname())
+	require.Error(t, err)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&attempts))
+}
@@ -0,0 +1,184 @@
+package jwks
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAlreadyStarted is returned by Start when the SyncingProvider's
+// background sync loop is already running.
+var ErrAlreadyStarted = errors.New("jwks: syncing provider already started")
+
+// syncRetryInitialBackoff is the starting backoff used by SyncingProvider
+// when a proactive sync fails; it doubles on each consecutive failure up to
+// CacheTTL/2.
+const syncRetryInitialBackoff = 1 * time.Second
+
+// SyncingProvider wraps a CachingProvider with a background goroutine that
+// proactively keeps the JWKS cache warm on a schedule, instead of only
+// refreshing lazily when CacheTTL elapses on an incoming request. On
+// failure it retries with exponential backoff rather than evicting the
+// cache, so a single bad fetch does not force the next request to pay for a
+// synchronous refresh against a possibly-still-unhealthy issuer. The cached
+// entry is evicted only once it is more than StaleWhileErrorTTL past its
+// expiry, as with CachingProvider.
+type SyncingProvider struct {
+	*CachingProvider
+
+	lifecycleMu sync.Mutex
+	cancel      context.CancelFunc
+	done        chan struct{}
+	healthy     atomic.Bool
+	running     atomic.Bool
+}
+
+// NewSyncingProvider builds a SyncingProvider around a new CachingProvider
+// configured with issuerURL, cacheTTL and opts. See NewCachingProvider for
+// the accepted option types. Call Start to begin the background sync loop.
+func NewSyncingProvider(issuerURL *url.URL, cacheTTL time.Duration, opts ...interface{}) *SyncingProvider {
+	return &SyncingProvider{
+		CachingProvider: NewCachingProvider(issuerURL, cacheTTL, opts...),
+	}
+}
+
+// Start performs an initial synchronous fetch of the JWKS and, once that
+// succeeds, spawns the background loop that keeps it in sync: sleeping
+// until around when the cached entry expires (up to 10% of the remaining
+// time earlier or later, so that many instances don't all poll the issuer
+// at once), refreshing, and on failure retrying with exponential backoff
+// (starting at 1s, capped at CacheTTL/2) while the cached entry keeps being
+// served within StaleWhileErrorTTL of its expiry. Start returns once the initial
+// fetch completes or ctx is done; the loop itself runs until Stop is
+// called. Calling Start again while the loop is already running returns
+// ErrAlreadyStarted without spawning a second loop.
+func (s *SyncingProvider) Start(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
+		return ErrAlreadyStarted
+	}
+
+	issuer := s.IssuerURL.Hostname()
+
+	if _, err := s.refreshKey(ctx, issuer); err != nil {
+		s.running.Store(false)
+		return err
+	}
+	s.healthy.Store(true)
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	s.lifecycleMu.Lock()
+	s.cancel = cancel
+	s.done = done
+	s.lifecycleMu.Unlock()
+
+	go s.syncLoop(loopCtx, issuer, done)
+
+	return nil
+}
+
+// Stop ends the background sync loop started by Start and waits for it to
+// exit. Calling Stop before Start, or more than once, is a no-op. Once Stop
+// returns, Start may be called again to restart the loop.
+func (s *SyncingProvider) Stop() {
+	s.lifecycleMu.Lock()
+	cancel := s.cancel
+	done := s.done
+	s.cancel = nil
+	s.done = nil
+	s.lifecycleMu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+	s.running.Store(false)
+}
+
+// Healthy reports whether the most recent sync — initial or background —
+// succeeded. Callers can wire this into a readiness probe.
+func (s *SyncingProvider) Healthy() bool {
+	return s.healthy.Load()
+}
+
+func (s *SyncingProvider) syncLoop(ctx context.Context, issuer string, done chan struct{}) {
+	defer close(done)
+
+	backoff := syncRetryInitialBackoff
+	maxBackoff := s.CacheTTL / 2
+	if maxBackoff <= 0 {
+		maxBackoff = syncRetryInitialBackoff
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.nextSyncDelay(issuer)):
+		}
+
+		// refreshKey reports no error when it falls back to a stale cached
+		// entry, so fetch directly to tell a failed sync from a good one.
+		s.CachingProvider.mu.Lock()
+		jwks, advertisedTTL, err := s.fetchJWKSWithRetry(ctx, issuer)
+		if err != nil {
+			_, _ = s.staleOrEvictLocked(ctx, issuer, err)
+		} else {
+			s.cache[issuer] = cachedJWKS{jwks: jwks, expiresAt: time.Now().Add(s.effectiveTTL(advertisedTTL))}
+		}
+		s.CachingProvider.mu.Unlock()
+
+		if err != nil {
+			s.healthy.Store(false)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+
+			continue
+		}
+
+		s.healthy.Store(true)
+		backoff = syncRetryInitialBackoff
+	}
+}
+
+// nextSyncDelay returns how long to sleep before the next proactive sync of
+// issuer: the time until the cached entry's expiresAt, jittered by ±10%.
+func (s *SyncingProvider) nextSyncDelay(issuer string) time.Duration {
+	s.CachingProvider.mu.RLock()
+	cached, ok := s.cache[issuer]
+	s.CachingProvider.mu.RUnlock()
+
+	if !ok {
+		return 0
+	}
+
+	delay := time.Until(cached.expiresAt)
+	if delay <= 0 {
+		return 0
+	}
+
+	jitter := time.Duration((rand.Float64()*2 - 1) * 0.1 * float64(delay))
+
+	delay += jitter
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay
+}
@@ -0,0 +1,103 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncingProviderStartAndStop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	sp := NewSyncingProvider(issuerURL, time.Hour, WithCustomJWKSURI(jwksURI))
+
+	require.NoError(t, sp.Start(context.Background()))
+	assert.True(t, sp.Healthy())
+
+	sp.Stop()
+}
+
+func TestSyncingProviderStartTwiceReturnsErrAlreadyStarted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	sp := NewSyncingProvider(issuerURL, time.Hour, WithCustomJWKSURI(jwksURI))
+	defer sp.Stop()
+
+	require.NoError(t, sp.Start(context.Background()))
+
+	err = sp.Start(context.Background())
+	assert.ErrorIs(t, err, ErrAlreadyStarted, "a second Start while the loop is running must not spawn another loop")
+}
+
+func TestSyncingProviderStartAfterStopRestartsLoop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "kid1", Algorithm: "HS256", Use: "sig"}},
+		})
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	sp := NewSyncingProvider(issuerURL, time.Hour, WithCustomJWKSURI(jwksURI))
+
+	require.NoError(t, sp.Start(context.Background()))
+	sp.Stop()
+
+	require.NoError(t, sp.Start(context.Background()), "Start after Stop should be able to run again")
+	sp.Stop()
+}
+
+func TestSyncingProviderStartReturnsErrorOnInitialFetchFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	jwksURI, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	issuerURL, err := url.Parse("https://issuer.example.com")
+	require.NoError(t, err)
+
+	sp := NewSyncingProvider(issuerURL, time.Hour, WithCustomJWKSURI(jwksURI))
+
+	require.Error(t, sp.Start(context.Background()))
+	assert.False(t, sp.Healthy())
+
+	// A failed Start must not have left running latched, so a retry can
+	// proceed without hitting ErrAlreadyStarted.
+	err = sp.Start(context.Background())
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrAlreadyStarted)
+}